@@ -1,137 +1,160 @@
 package main
 
 import (
-	"bytes"
 	"context"
+	"flag"
 	"fmt"
-	"image"
-	"image/jpeg"
-	"io"
 	"log"
-	"net/http"
 	"os"
-	"path/filepath"
-	"sync"
+	"strconv"
+	"strings"
 
-	"github.com/nfnt/resize"
-	"go.mongodb.org/mongo-driver/bson"
+	"PodGoImg/pkg/imgpipeline"
+
+	"github.com/HugoSmits86/nativewebp"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func main() {
+	encodeCfg := imgpipeline.DefaultEncodeConfig()
+	fetchCfg := imgpipeline.DefaultFetchConfig()
+	outputDir := "img"
+	cachePath := ".cache"
+	schedulerCount := 10
+	store := "fs"
+	inlineMaxSize := uint(64)
+
+	sizesFlag := flag.String("sizes", joinSizes(encodeCfg.Sizes), "comma-separated long-edge sizes (px) to generate")
+	flag.StringVar(&outputDir, "out", outputDir, "directory to write encoded images to")
+	flag.IntVar(&encodeCfg.JPEGQuality, "jpeg-quality", encodeCfg.JPEGQuality, "JPEG encoding quality (1-100)")
+	flag.IntVar(&encodeCfg.AVIFQuality, "avif-quality", encodeCfg.AVIFQuality, "AVIF encoding quality (0-100)")
+	flag.IntVar(&encodeCfg.AVIFSpeed, "avif-speed", encodeCfg.AVIFSpeed, "AVIF encoding speed (0-10, higher is faster/lower quality)")
+	webpCompression := flag.Int("webp-compression", int(encodeCfg.WebPCompression), "WebP encoding effort (0-6, higher is smaller/slower)")
+	flag.IntVar(&encodeCfg.BlurHashXComponents, "blurhash-x", encodeCfg.BlurHashXComponents, "BlurHash horizontal component count")
+	flag.IntVar(&encodeCfg.BlurHashYComponents, "blurhash-y", encodeCfg.BlurHashYComponents, "BlurHash vertical component count")
+	flag.StringVar(&cachePath, "cache-path", cachePath, "directory used to cache downloaded source images by content hash")
+	flag.DurationVar(&fetchCfg.HTTPTimeout, "http-timeout", fetchCfg.HTTPTimeout, "per-request timeout when downloading source images")
+	flag.Int64Var(&fetchCfg.MaxImageBytes, "max-image-bytes", fetchCfg.MaxImageBytes, "maximum source image size in bytes")
+	flag.IntVar(&fetchCfg.RetryAttempts, "retry-attempts", fetchCfg.RetryAttempts, "retries for 5xx/transient download errors")
+	flag.IntVar(&schedulerCount, "scheduler-count", schedulerCount, "number of images processed concurrently")
+	flag.StringVar(&store, "store", store, "where to persist encoded variants: fs|mongo|gridfs")
+	flag.UintVar(&inlineMaxSize, "inline-max-size", inlineMaxSize, "in --store=mongo, variants at or below this long-edge size are embedded inline; larger ones go to GridFS")
+	flag.Parse()
+
+	encodeCfg.WebPCompression = nativewebp.CompressionLevel(*webpCompression)
+
+	sizes, err := parseSizes(*sizesFlag)
+	if err != nil {
+		log.Fatalf("Invalid --sizes: %v", err)
+	}
+	encodeCfg.Sizes = sizes
+
+	if err := os.MkdirAll(cachePath, os.ModePerm); err != nil {
+		log.Fatalf("Failed to create cache directory: %v", err)
+	}
+
+	ctx := context.Background()
+
 	// MongoDB connection setup
 	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
-	client, err := mongo.Connect(context.TODO(), clientOptions)
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
 
-	// Select the database and collection
-	collection := client.Database("podgo").Collection("podcasts")
-
-	// Define the projection to only include the podlistUrl and image fields
-	projection := bson.D{
-		{Key: "podlistUrl", Value: 1},
-		{Key: "image", Value: 1},
-	}
+	db := client.Database("podgo")
 
-	// Find all documents in the collection with the specified projection
-	cur, err := collection.Find(context.TODO(), bson.D{}, options.Find().SetProjection(projection))
+	source, err := imgpipeline.NewMongoSource(ctx, db.Collection("podcasts"))
 	if err != nil {
-		log.Fatalf("Failed to find documents: %v", err)
+		log.Fatalf("Failed to query podcasts: %v", err)
 	}
-	defer cur.Close(context.TODO())
+	defer source.Close(ctx)
 
-	// Ensure the target directory exists
-	if err := os.MkdirAll("img", os.ModePerm); err != nil {
-		log.Fatalf("Failed to create directory: %v", err)
+	variantStore, err := newVariantStore(store, outputDir, inlineMaxSize, db)
+	if err != nil {
+		log.Fatalf("Invalid --store: %v", err)
 	}
 
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 10) // Limit to 10 concurrent goroutines
-
-	for cur.Next(context.TODO()) {
-		var podcast struct {
-			PodlistUrl string `bson:"podlistUrl"`
-			Image      string `bson:"image"`
-		}
-
-		err := cur.Decode(&podcast)
-		if err != nil {
-			log.Printf("Failed to decode document: %v", err)
-			continue
-		}
-
-		wg.Add(1)
-		sem <- struct{}{} // Acquire a token
-		go func(podcast struct {
-			PodlistUrl string `bson:"podlistUrl"`
-			Image      string `bson:"image"`
-		}) {
-			defer wg.Done()
-			defer func() { <-sem }() // Release the token
-
-			// Download the image
-			imageData, err := downloadImage(podcast.Image)
-			if err != nil {
-				log.Printf("Failed to download image: %s, error: %v", podcast.Image, err)
-				return
-			}
-
-			// Resize the image
-			resizedImg, err := resizeImage(imageData, 800, 800) // Resize to 800x800
-			if err != nil {
-				log.Printf("Failed to resize image: %v", err)
-				return
-			}
-
-			// Save the image as a JPEG
-			err = saveImage(resizedImg, filepath.Join("img", podcast.PodlistUrl+".jpg"))
-			if err != nil {
-				log.Printf("Failed to save image: %v", err)
-			}
-		}(podcast)
+	pipeline := &imgpipeline.Pipeline{
+		Source: source,
+		Fetcher: &imgpipeline.CachingFetcher{
+			Fetcher: imgpipeline.NewHTTPFetcher(fetchCfg),
+			Path:    cachePath,
+		},
+		Transformer: &imgpipeline.EncodingTransformer{
+			Config: encodeCfg,
+			Store:  variantStore,
+		},
+		Sink: &imgpipeline.MongoSink{
+			Podcasts: db.Collection("podcasts"),
+			Failures: db.Collection("failures"),
+			Config:   encodeCfg,
+		},
+		Concurrency: schedulerCount,
 	}
 
-	if err := cur.Err(); err != nil {
-		log.Fatalf("Cursor error: %v", err)
+	if err := pipeline.Run(ctx); err != nil {
+		log.Fatalf("Pipeline run failed: %v", err)
 	}
 
-	wg.Wait()
 	fmt.Println("All images processed.")
 }
 
-func downloadImage(url string) (image.Image, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get image: %w", err)
+// newVariantStore builds the imgpipeline.VariantStore for the requested
+// --store mode: "fs" writes files under outputDir, "mongo" embeds variants
+// at or below inlineMaxSize inline and sends the rest to GridFS, and
+// "gridfs" sends every variant to GridFS.
+func newVariantStore(mode, outputDir string, inlineMaxSize uint, db *mongo.Database) (imgpipeline.VariantStore, error) {
+	switch mode {
+	case "fs":
+		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+		return &imgpipeline.FSVariantStore{OutputDir: outputDir}, nil
+	case "mongo", "gridfs":
+		bucket, err := gridfs.NewBucket(db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open GridFS bucket: %w", err)
+		}
+		store := &imgpipeline.MongoVariantStore{
+			Podcasts: db.Collection("podcasts"),
+			Bucket:   bucket,
+		}
+		if mode == "mongo" {
+			store.InlineMaxSize = inlineMaxSize
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown store mode %q (want fs, mongo, or gridfs)", mode)
 	}
-	defer resp.Body.Close()
+}
 
-	imgData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read image data: %w", err)
+func parseSizes(s string) ([]uint, error) {
+	parts := strings.Split(s, ",")
+	sizes := make([]uint, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q: %w", p, err)
+		}
+		sizes = append(sizes, uint(n))
 	}
-
-	img, _, err := image.Decode(bytes.NewReader(imgData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no sizes given")
 	}
-	return img, nil
+	return sizes, nil
 }
 
-func resizeImage(img image.Image, width, height uint) (image.Image, error) {
-	return resize.Resize(width, height, img, resize.Lanczos3), nil
-}
-
-func saveImage(img image.Image, path string) error {
-	out, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+func joinSizes(sizes []uint) string {
+	parts := make([]string, len(sizes))
+	for i, s := range sizes {
+		parts[i] = strconv.FormatUint(uint64(s), 10)
 	}
-	defer out.Close()
-
-	options := &jpeg.Options{Quality: 75} // Optimize JPEG
-	return jpeg.Encode(out, img, options)
+	return strings.Join(parts, ",")
 }
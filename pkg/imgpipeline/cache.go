@@ -0,0 +1,55 @@
+package imgpipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CachingFetcher wraps another Fetcher with a content-addressed on-disk
+// cache of raw source bytes, keyed by their sha256. The origin is always
+// consulted first, via conditional GET, so a genuinely changed image is
+// never served stale; the cache only steps in when the wrapped Fetcher
+// returns an error (of any kind, not only an unreachable origin) and
+// Item.ImageHash names bytes a prior run already has on disk, so a flaky
+// feed doesn't fail an otherwise-resumable run.
+type CachingFetcher struct {
+	Fetcher Fetcher
+	Path    string
+}
+
+// Fetch implements Fetcher.
+func (c *CachingFetcher) Fetch(ctx context.Context, item Item) (FetchResult, error) {
+	result, err := c.Fetcher.Fetch(ctx, item)
+	if err != nil {
+		if item.ImageHash == "" {
+			return result, err
+		}
+		data, cacheErr := c.read(item.ImageHash)
+		if cacheErr != nil {
+			return result, err
+		}
+		return FetchResult{Data: data, ETag: item.ETag, LastModified: item.LastModified}, nil
+	}
+	if result.NotModified {
+		return result, nil
+	}
+
+	if err := c.write(HashBytes(result.Data), result.Data); err != nil {
+		return result, fmt.Errorf("cache write: %w", err)
+	}
+	return result, nil
+}
+
+func (c *CachingFetcher) read(hash string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(c.Path, hash))
+}
+
+func (c *CachingFetcher) write(hash string, data []byte) error {
+	path := filepath.Join(c.Path, hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return os.WriteFile(path, data, 0o644)
+}
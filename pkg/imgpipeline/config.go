@@ -0,0 +1,58 @@
+package imgpipeline
+
+import (
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gen2brain/avif"
+)
+
+// DefaultSizes are the long-edge pixel sizes emitted for every source image,
+// largest first, matching the srcset a responsive <picture> element expects.
+var DefaultSizes = []uint{1600, 800, 400, 200, 64}
+
+// EncodeConfig holds the tunable parameters of the resize/encode stage:
+// which sizes to emit and the per-format quality/effort tradeoff to encode
+// them with.
+type EncodeConfig struct {
+	Sizes []uint
+
+	JPEGQuality int
+
+	AVIFQuality int
+	AVIFSpeed   int
+
+	WebPCompression nativewebp.CompressionLevel
+
+	BlurHashXComponents int
+	BlurHashYComponents int
+}
+
+// FetchConfig holds the tunable parameters of the download stage.
+type FetchConfig struct {
+	HTTPTimeout   time.Duration
+	MaxImageBytes int64
+	RetryAttempts int
+}
+
+// DefaultEncodeConfig returns the encode settings used when no flags override them.
+func DefaultEncodeConfig() EncodeConfig {
+	return EncodeConfig{
+		Sizes:               DefaultSizes,
+		JPEGQuality:         75,
+		AVIFQuality:         avif.DefaultQuality,
+		AVIFSpeed:           avif.DefaultSpeed,
+		WebPCompression:     nativewebp.DefaultCompression,
+		BlurHashXComponents: 4,
+		BlurHashYComponents: 3,
+	}
+}
+
+// DefaultFetchConfig returns the download settings used when no flags override them.
+func DefaultFetchConfig() FetchConfig {
+	return FetchConfig{
+		HTTPTimeout:   15 * time.Second,
+		MaxImageBytes: 10 << 20, // 10 MiB
+		RetryAttempts: 3,
+	}
+}
@@ -0,0 +1,104 @@
+package imgpipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// retryableError marks a fetch failure as a transient condition (a 5xx
+// response or a network-level error) worth retrying, as opposed to a
+// permanent client error like a bad content type or an oversized body.
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// HTTPFetcher downloads an Item's image over HTTP(S), sending conditional-GET
+// validators, capping response size, validating content type, and retrying
+// transient failures with exponential backoff.
+type HTTPFetcher struct {
+	Client *http.Client
+	Config FetchConfig
+}
+
+// NewHTTPFetcher returns a Fetcher configured with its own timeout-bound
+// *http.Client.
+func NewHTTPFetcher(cfg FetchConfig) *HTTPFetcher {
+	return &HTTPFetcher{
+		Client: &http.Client{Timeout: cfg.HTTPTimeout},
+		Config: cfg,
+	}
+}
+
+// Fetch implements Fetcher.
+func (f *HTTPFetcher) Fetch(ctx context.Context, item Item) (FetchResult, error) {
+	backoff := 500 * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		result, err := f.fetchOnce(ctx, item)
+		if err == nil {
+			return result, nil
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt == f.Config.RetryAttempts {
+			return FetchResult{}, err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (f *HTTPFetcher) fetchOnce(ctx context.Context, item Item) (FetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, item.URL, nil)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("failed to build request: %w", err)
+	}
+	if item.ETag != "" {
+		req.Header.Set("If-None-Match", item.ETag)
+	}
+	if item.LastModified != "" {
+		req.Header.Set("If-Modified-Since", item.LastModified)
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return FetchResult{}, &retryableError{fmt.Errorf("failed to get image: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return FetchResult{ETag: item.ETag, LastModified: item.LastModified, NotModified: true}, nil
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return FetchResult{}, &retryableError{fmt.Errorf("server error: %s", resp.Status)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return FetchResult{}, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(strings.ToLower(contentType), "image/") {
+		return FetchResult{}, fmt.Errorf("unexpected content type: %q", contentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.Config.MaxImageBytes+1))
+	if err != nil {
+		return FetchResult{}, &retryableError{fmt.Errorf("failed to read image data: %w", err)}
+	}
+	if int64(len(data)) > f.Config.MaxImageBytes {
+		return FetchResult{}, fmt.Errorf("image exceeds max size of %d bytes", f.Config.MaxImageBytes)
+	}
+
+	return FetchResult{
+		Data:         data,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
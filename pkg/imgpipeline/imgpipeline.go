@@ -0,0 +1,203 @@
+// Package imgpipeline implements the download -> decode -> resize -> encode
+// -> persist pipeline behind the PodGoImg command, as a set of small
+// interfaces driven by a worker-pool scheduler. main is a thin CLI wrapper
+// around a Pipeline; embedders can swap in their own Source/Fetcher/
+// Transformer/Sink to reuse the pipeline as a library.
+package imgpipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+)
+
+// Item is one unit of work flowing through the pipeline: a source image to
+// fetch, keyed by Name for output file naming, plus whatever validators the
+// last successful run recorded so the pipeline can skip unchanged images.
+type Item struct {
+	// ID opaquely identifies the item to its Source and Sink (e.g. a Mongo
+	// ObjectID hex string, or a file path).
+	ID   string
+	Name string
+	URL  string
+
+	ETag         string
+	LastModified string
+	ImageHash    string
+}
+
+// FetchResult is what a Fetcher returns for an Item.
+type FetchResult struct {
+	Data         []byte
+	ETag         string
+	LastModified string
+	// NotModified is true when the source reported the item unchanged
+	// (e.g. an HTTP 304), in which case Data is empty.
+	NotModified bool
+}
+
+// Result is what a Sink persists after an Item has been fetched, decoded,
+// and transformed successfully.
+type Result struct {
+	Item         Item
+	Image        image.Image
+	ImageHash    string
+	ETag         string
+	LastModified string
+}
+
+// Source produces the items to process, one at a time. Next returns io.EOF
+// once the source is exhausted. If a single item can't be produced (e.g. one
+// bad document in an otherwise-fine collection), Next should return a
+// *SkipError rather than a plain error, so Pipeline.Run records it and moves
+// on instead of aborting the whole run.
+type Source interface {
+	Next(ctx context.Context) (Item, error)
+}
+
+// SkipError is returned by a Source's Next when a single item is unusable
+// but the rest of the source should still be processed. Item carries
+// whatever identifying information is available for Sink.Fail; it may be
+// the zero value if even that couldn't be determined.
+type SkipError struct {
+	Item Item
+	Err  error
+}
+
+func (e *SkipError) Error() string { return e.Err.Error() }
+func (e *SkipError) Unwrap() error { return e.Err }
+
+// Fetcher retrieves the raw bytes for an Item.
+type Fetcher interface {
+	Fetch(ctx context.Context, item Item) (FetchResult, error)
+}
+
+// Transformer turns a decoded image into whatever a consumer needs (resized,
+// re-encoded variants on disk, uploads, etc).
+type Transformer interface {
+	Transform(ctx context.Context, item Item, img image.Image) error
+}
+
+// Sink persists the outcome of processing an Item, successful or not.
+type Sink interface {
+	Put(ctx context.Context, result Result) error
+	Fail(ctx context.Context, item Item, stage string, err error) error
+}
+
+// Pipeline wires a Source through a Fetcher, Transformer, and Sink, driven
+// by a worker pool of Concurrency goroutines.
+type Pipeline struct {
+	Source      Source
+	Fetcher     Fetcher
+	Transformer Transformer
+	Sink        Sink
+	Concurrency int
+}
+
+// Run drains Source, processing every Item concurrently up to
+// p.Concurrency, and blocks until all of them have been handled. It returns
+// the first error the Source itself produced (errors from individual items
+// are routed to Sink.Fail and don't stop the run).
+func (p *Pipeline) Run(ctx context.Context) error {
+	concurrency := p.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for {
+		item, err := p.Source.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var skip *SkipError
+		if errors.As(err, &skip) {
+			p.fail(ctx, skip.Item, "source", skip.Err)
+			continue
+		}
+		if err != nil {
+			wg.Wait()
+			return fmt.Errorf("source: %w", err)
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item Item) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			p.process(ctx, item)
+		}(item)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+func (p *Pipeline) process(ctx context.Context, item Item) {
+	fr, err := p.Fetcher.Fetch(ctx, item)
+	if err != nil {
+		p.fail(ctx, item, "download", err)
+		return
+	}
+	if fr.NotModified {
+		return
+	}
+
+	hash := HashBytes(fr.Data)
+	if hash == item.ImageHash {
+		return
+	}
+
+	img, err := decodeImage(fr.Data)
+	if err != nil {
+		p.fail(ctx, item, "decode", err)
+		return
+	}
+
+	if err := p.Transformer.Transform(ctx, item, img); err != nil {
+		p.fail(ctx, item, "encode", err)
+		return
+	}
+
+	result := Result{
+		Item:         item,
+		Image:        img,
+		ImageHash:    hash,
+		ETag:         fr.ETag,
+		LastModified: fr.LastModified,
+	}
+	if err := p.Sink.Put(ctx, result); err != nil {
+		p.fail(ctx, item, "update", err)
+	}
+}
+
+func (p *Pipeline) fail(ctx context.Context, item Item, stage string, err error) {
+	if sinkErr := p.Sink.Fail(ctx, item, stage, err); sinkErr != nil {
+		// The original error is the one worth surfacing; the sink is only
+		// best-effort bookkeeping for operators.
+		_ = sinkErr
+	}
+}
+
+func decodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// HashBytes returns a hex-encoded sha256 of data, used both as the cache key
+// for raw source bytes and as the ImageHash recorded on successful items.
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
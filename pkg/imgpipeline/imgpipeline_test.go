@@ -0,0 +1,170 @@
+package imgpipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sync"
+	"testing"
+)
+
+// fakeSource yields a fixed slice of items, then io.EOF.
+type fakeSource struct {
+	items []Item
+	pos   int
+}
+
+func (s *fakeSource) Next(ctx context.Context) (Item, error) {
+	if s.pos >= len(s.items) {
+		return Item{}, io.EOF
+	}
+	item := s.items[s.pos]
+	s.pos++
+	return item, nil
+}
+
+// fakeFetcher returns a canned FetchResult or error per item URL.
+type fakeFetcher struct {
+	data map[string][]byte
+	errs map[string]error
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, item Item) (FetchResult, error) {
+	if err, ok := f.errs[item.URL]; ok {
+		return FetchResult{}, err
+	}
+	return FetchResult{Data: f.data[item.URL], ETag: "etag-" + item.Name}, nil
+}
+
+// fakeTransformer records which items it was asked to transform.
+type fakeTransformer struct {
+	mu    sync.Mutex
+	calls []string
+	err   error
+}
+
+func (t *fakeTransformer) Transform(ctx context.Context, item Item, img image.Image) error {
+	if t.err != nil {
+		return t.err
+	}
+	t.mu.Lock()
+	t.calls = append(t.calls, item.Name)
+	t.mu.Unlock()
+	return nil
+}
+
+// memorySink is an in-memory Sink for tests, recording every successful
+// Result and failure it's given.
+type memorySink struct {
+	mu       sync.Mutex
+	results  []Result
+	failures []string
+}
+
+func (s *memorySink) Put(ctx context.Context, result Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, result)
+	return nil
+}
+
+func (s *memorySink) Fail(ctx context.Context, item Item, stage string, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, item.Name+":"+stage)
+	return nil
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to build test PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPipelineRun_FetchesTransformsAndPersists(t *testing.T) {
+	data := testPNG(t)
+	source := &fakeSource{items: []Item{{Name: "show-a", URL: "http://example.com/a.png"}}}
+	transformer := &fakeTransformer{}
+	sink := &memorySink{}
+
+	p := &Pipeline{
+		Source:      source,
+		Fetcher:     &fakeFetcher{data: map[string][]byte{"http://example.com/a.png": data}},
+		Transformer: transformer,
+		Sink:        sink,
+		Concurrency: 2,
+	}
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if len(transformer.calls) != 1 || transformer.calls[0] != "show-a" {
+		t.Fatalf("expected transform call for show-a, got %v", transformer.calls)
+	}
+	if len(sink.results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(sink.results))
+	}
+	if want := HashBytes(data); sink.results[0].ImageHash != want {
+		t.Errorf("ImageHash = %q, want %q", sink.results[0].ImageHash, want)
+	}
+	if len(sink.failures) != 0 {
+		t.Errorf("expected no failures, got %v", sink.failures)
+	}
+}
+
+func TestPipelineRun_SkipsWhenImageHashUnchanged(t *testing.T) {
+	data := testPNG(t)
+	source := &fakeSource{items: []Item{{Name: "show-a", URL: "http://example.com/a.png", ImageHash: HashBytes(data)}}}
+	transformer := &fakeTransformer{}
+	sink := &memorySink{}
+
+	p := &Pipeline{
+		Source:      source,
+		Fetcher:     &fakeFetcher{data: map[string][]byte{"http://example.com/a.png": data}},
+		Transformer: transformer,
+		Sink:        sink,
+		Concurrency: 1,
+	}
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if len(transformer.calls) != 0 {
+		t.Errorf("expected no transform calls for unchanged content, got %v", transformer.calls)
+	}
+	if len(sink.results) != 0 {
+		t.Errorf("expected no results for unchanged content, got %v", sink.results)
+	}
+}
+
+func TestPipelineRun_RecordsFetchFailure(t *testing.T) {
+	source := &fakeSource{items: []Item{{Name: "show-a", URL: "http://example.com/a.png"}}}
+	sink := &memorySink{}
+
+	p := &Pipeline{
+		Source:      source,
+		Fetcher:     &fakeFetcher{errs: map[string]error{"http://example.com/a.png": errors.New("boom")}},
+		Transformer: &fakeTransformer{},
+		Sink:        sink,
+		Concurrency: 1,
+	}
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if len(sink.failures) != 1 || sink.failures[0] != "show-a:download" {
+		t.Fatalf("expected a download failure for show-a, got %v", sink.failures)
+	}
+}
@@ -0,0 +1,164 @@
+package imgpipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/buckket/go-blurhash"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// podcastDoc is the subset of a podcast document the pipeline reads and
+// writes. ImageHash, ETag, and LastModified reflect whatever was stored by
+// the last successful run, and drive the incremental skip logic.
+type podcastDoc struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	PodlistUrl   string             `bson:"podlistUrl"`
+	Image        string             `bson:"image"`
+	ImageHash    string             `bson:"imageHash"`
+	ETag         string             `bson:"etag"`
+	LastModified string             `bson:"lastModified"`
+}
+
+var podcastProjection = bson.D{
+	{Key: "podlistUrl", Value: 1},
+	{Key: "image", Value: 1},
+	{Key: "imageHash", Value: 1},
+	{Key: "etag", Value: 1},
+	{Key: "lastModified", Value: 1},
+}
+
+// MongoSource streams podcast documents out of a MongoDB collection as
+// pipeline Items, projected down to the fields the pipeline needs.
+type MongoSource struct {
+	cursor *mongo.Cursor
+}
+
+// NewMongoSource runs the podcast query and returns a Source over its cursor.
+func NewMongoSource(ctx context.Context, collection *mongo.Collection) (*MongoSource, error) {
+	cur, err := collection.Find(ctx, bson.D{}, options.Find().SetProjection(podcastProjection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find documents: %w", err)
+	}
+	return &MongoSource{cursor: cur}, nil
+}
+
+// Next implements Source.
+func (s *MongoSource) Next(ctx context.Context) (Item, error) {
+	if !s.cursor.Next(ctx) {
+		if err := s.cursor.Err(); err != nil {
+			return Item{}, fmt.Errorf("cursor error: %w", err)
+		}
+		return Item{}, io.EOF
+	}
+
+	var doc podcastDoc
+	if err := s.cursor.Decode(&doc); err != nil {
+		return Item{}, &SkipError{
+			Item: Item{ID: rawDocumentID(s.cursor.Current)},
+			Err:  fmt.Errorf("failed to decode document: %w", err),
+		}
+	}
+
+	return Item{
+		ID:           doc.ID.Hex(),
+		Name:         doc.PodlistUrl,
+		URL:          doc.Image,
+		ETag:         doc.ETag,
+		LastModified: doc.LastModified,
+		ImageHash:    doc.ImageHash,
+	}, nil
+}
+
+// Close releases the underlying cursor.
+func (s *MongoSource) Close(ctx context.Context) error {
+	return s.cursor.Close(ctx)
+}
+
+// rawDocumentID best-effort recovers a document's _id without relying on the
+// rest of it having decoded cleanly, so a failed decode can still be
+// reported against the right document.
+func rawDocumentID(raw bson.Raw) string {
+	val, err := raw.LookupErr("_id")
+	if err != nil {
+		return ""
+	}
+	if oid, ok := val.ObjectIDOK(); ok {
+		return oid.Hex()
+	}
+	return ""
+}
+
+// failureRecord is a structured, per-URL error report persisted to the
+// "failures" collection so operators can triage broken feeds without
+// trawling logs.
+type failureRecord struct {
+	PodlistUrl string    `bson:"podlistUrl"`
+	Image      string    `bson:"image"`
+	Stage      string    `bson:"stage"`
+	Error      string    `bson:"error"`
+	Time       time.Time `bson:"time"`
+}
+
+// MongoSink writes BlurHash/dominant-color/dimension placeholders and
+// content-cache metadata back onto the source podcast document, and records
+// failures into a sibling "failures" collection.
+type MongoSink struct {
+	Podcasts *mongo.Collection
+	Failures *mongo.Collection
+	Config   EncodeConfig
+}
+
+// Put implements Sink.
+func (s *MongoSink) Put(ctx context.Context, result Result) error {
+	thumb := resizeToLongEdge(result.Image, 64)
+
+	hash, err := blurhash.Encode(s.Config.BlurHashXComponents, s.Config.BlurHashYComponents, thumb)
+	if err != nil {
+		return fmt.Errorf("blurhash encode: %w", err)
+	}
+
+	b := result.Image.Bounds()
+	update := bson.M{
+		"blurhash":      hash,
+		"dominantColor": dominantColor(thumb),
+		"width":         b.Dx(),
+		"height":        b.Dy(),
+		"imageHash":     result.ImageHash,
+		"etag":          result.ETag,
+		"lastModified":  result.LastModified,
+	}
+
+	id, err := primitive.ObjectIDFromHex(result.Item.ID)
+	if err != nil {
+		return fmt.Errorf("invalid item id: %w", err)
+	}
+
+	if _, err := s.Podcasts.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update}); err != nil {
+		return fmt.Errorf("update document: %w", err)
+	}
+	return nil
+}
+
+// Fail implements Sink.
+func (s *MongoSink) Fail(ctx context.Context, item Item, stage string, err error) error {
+	log.Printf("Failed to %s image for %s: %v", stage, item.Name, err)
+
+	record := failureRecord{
+		PodlistUrl: item.Name,
+		Image:      item.URL,
+		Stage:      stage,
+		Error:      err.Error(),
+		Time:       time.Now(),
+	}
+	if _, insertErr := s.Failures.InsertOne(ctx, record); insertErr != nil {
+		return fmt.Errorf("record failure: %w", insertErr)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package imgpipeline
+
+import (
+	"context"
+	"io"
+)
+
+// FileListSource is a Source over a fixed, in-memory list of Items. It's
+// useful for one-off batches and for driving the pipeline from something
+// other than MongoDB, e.g. a list of paths read off the filesystem or
+// parsed out of an HTTP feed.
+type FileListSource struct {
+	items []Item
+	pos   int
+}
+
+// NewFileListSource returns a Source that yields items in order.
+func NewFileListSource(items []Item) *FileListSource {
+	return &FileListSource{items: items}
+}
+
+// Next implements Source.
+func (s *FileListSource) Next(ctx context.Context) (Item, error) {
+	if s.pos >= len(s.items) {
+		return Item{}, io.EOF
+	}
+	item := s.items[s.pos]
+	s.pos++
+	return item, nil
+}
@@ -0,0 +1,106 @@
+package imgpipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FSVariantStore writes each Variant to OutputDir, named
+// "<item.Name>_<size>.<format>", e.g. "img/myshow_800.avif". This is the
+// --store=fs mode, and what the pipeline always did before GridFS/inline
+// storage existed.
+type FSVariantStore struct {
+	OutputDir string
+}
+
+// Store implements VariantStore.
+func (s *FSVariantStore) Store(ctx context.Context, item Item, variant Variant) error {
+	path := filepath.Join(s.OutputDir, fmt.Sprintf("%s_%d.%s", item.Name, variant.Size, variant.Format))
+	if err := os.WriteFile(path, variant.Data, 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// MongoVariantStore persists variants onto the podcast document itself,
+// keeping it self-contained in deployments with no persistent local disk.
+// Variants at or below InlineMaxSize are embedded directly as BSON binary;
+// larger ones are uploaded to GridFS and referenced by their file ID. Set
+// InlineMaxSize to 0 to send everything through GridFS (--store=gridfs).
+type MongoVariantStore struct {
+	Podcasts      *mongo.Collection
+	Bucket        *gridfs.Bucket
+	InlineMaxSize uint
+}
+
+// Store implements VariantStore.
+func (s *MongoVariantStore) Store(ctx context.Context, item Item, variant Variant) error {
+	id, err := primitive.ObjectIDFromHex(item.ID)
+	if err != nil {
+		return fmt.Errorf("invalid item id: %w", err)
+	}
+	field := fmt.Sprintf("variants.%d.%s", variant.Size, variant.Format)
+
+	oldFileID, err := s.existingFileID(ctx, id, field)
+	if err != nil {
+		return fmt.Errorf("look up existing variant: %w", err)
+	}
+
+	var value interface{}
+	if variant.Size <= s.InlineMaxSize {
+		value = primitive.Binary{Data: variant.Data}
+	} else {
+		filename := fmt.Sprintf("%s_%d.%s", item.Name, variant.Size, variant.Format)
+		fileID, err := s.Bucket.UploadFromStream(filename, bytes.NewReader(variant.Data))
+		if err != nil {
+			return fmt.Errorf("gridfs upload: %w", err)
+		}
+		value = fileID
+	}
+
+	if _, err := s.Podcasts.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{field: value}}); err != nil {
+		return fmt.Errorf("update document: %w", err)
+	}
+
+	if oldFileID != nil {
+		if err := s.Bucket.Delete(*oldFileID); err != nil {
+			return fmt.Errorf("delete superseded gridfs file: %w", err)
+		}
+	}
+	return nil
+}
+
+// existingFileID returns the GridFS file ID currently stored at field, if
+// any, so Store can delete it once the replacement upload succeeds.
+// Inline (non-GridFS) values aren't ObjectIDs and are ignored.
+func (s *MongoVariantStore) existingFileID(ctx context.Context, id primitive.ObjectID, field string) (*primitive.ObjectID, error) {
+	opts := options.FindOne().SetProjection(bson.M{field: 1})
+	var doc bson.Raw
+	err := s.Podcasts.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := doc.LookupErr(strings.Split(field, ".")...)
+	if err != nil {
+		return nil, nil
+	}
+	oid, ok := val.ObjectIDOK()
+	if !ok {
+		return nil, nil
+	}
+	return &oid, nil
+}
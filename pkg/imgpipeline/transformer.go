@@ -0,0 +1,121 @@
+package imgpipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gen2brain/avif"
+	"github.com/nfnt/resize"
+)
+
+// Variant is one resized, re-encoded copy of a source image.
+type Variant struct {
+	// Size is the long-edge pixel size this variant was resized to.
+	Size uint
+	// Format is the file extension without a dot, e.g. "jpg", "webp", "avif".
+	Format string
+	Data   []byte
+}
+
+// VariantStore persists a single encoded Variant wherever a Transformer's
+// --store mode calls for: local disk, inline in MongoDB, or GridFS.
+type VariantStore interface {
+	Store(ctx context.Context, item Item, variant Variant) error
+}
+
+// EncodingTransformer resizes an image to each of Config.Sizes, preserving
+// aspect ratio, encodes a JPEG, WebP, and AVIF copy of each, and hands every
+// encoded Variant to Store.
+type EncodingTransformer struct {
+	Config EncodeConfig
+	Store  VariantStore
+}
+
+// Transform implements Transformer.
+func (t *EncodingTransformer) Transform(ctx context.Context, item Item, img image.Image) error {
+	for _, size := range t.Config.Sizes {
+		resized := resizeToLongEdge(img, size)
+
+		for _, format := range [...]string{"jpg", "webp", "avif"} {
+			data, err := encodeVariant(format, resized, t.Config)
+			if err != nil {
+				return fmt.Errorf("%s %dpx: %w", format, size, err)
+			}
+
+			variant := Variant{Size: size, Format: format, Data: data}
+			if err := t.Store.Store(ctx, item, variant); err != nil {
+				return fmt.Errorf("store %s %dpx: %w", format, size, err)
+			}
+		}
+	}
+	return nil
+}
+
+func encodeVariant(format string, img image.Image, cfg EncodeConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "jpg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: cfg.JPEGQuality})
+	case "webp":
+		err = nativewebp.Encode(&buf, img, &nativewebp.Options{CompressionLevel: cfg.WebPCompression})
+	case "avif":
+		err = avif.Encode(&buf, img, avif.Options{Quality: cfg.AVIFQuality, Speed: cfg.AVIFSpeed})
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resizeToLongEdge scales img so its longer edge equals size, preserving
+// aspect ratio. The short edge is computed explicitly and clamped to at
+// least 1px, since an extreme aspect ratio scaled down to a small size
+// (e.g. the 64px default) can otherwise round to 0 and produce a
+// zero-dimension image that fails to encode.
+func resizeToLongEdge(img image.Image, size uint) image.Image {
+	if size < 1 {
+		size = 1
+	}
+
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+
+	var newW, newH uint
+	if w >= h {
+		newW = size
+		newH = uint(math.Max(1, math.Round(float64(size)*h/w)))
+	} else {
+		newH = size
+		newW = uint(math.Max(1, math.Round(float64(size)*w/h)))
+	}
+	return resize.Resize(newW, newH, img, resize.Lanczos3)
+}
+
+// dominantColor returns the average color of img as a "#rrggbb" hex string,
+// a cheap stand-in for a true dominant-color extraction.
+func dominantColor(img image.Image) string {
+	b := img.Bounds()
+	var rSum, gSum, bSum, count uint64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			rSum += uint64(r >> 8)
+			gSum += uint64(g >> 8)
+			bSum += uint64(bl >> 8)
+			count++
+		}
+	}
+	if count == 0 {
+		return "#000000"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", rSum/count, gSum/count, bSum/count)
+}